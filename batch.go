@@ -0,0 +1,128 @@
+package orion
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// ActionType selects the semantics of a BatchUpdate request.
+type ActionType string
+
+const (
+	ActionAppend       ActionType = "append"
+	ActionAppendStrict ActionType = "appendStrict"
+	ActionUpdate       ActionType = "update"
+	ActionReplace      ActionType = "replace"
+	ActionDelete       ActionType = "delete"
+)
+
+type wireBatchUpdate struct {
+	ActionType ActionType               `json:"actionType"`
+	Entities   []map[string]interface{} `json:"entities"`
+}
+
+// BatchUpdate creates, updates, or deletes many entities in a single
+// request, wrapping POST /v2/op/update. action controls whether existing
+// attributes are appended, replaced, or removed; see the ActionType
+// constants.
+func (self *Server) BatchUpdate(ctx context.Context, action ActionType, entities []Entity, scope ...Scope) error {
+	body := wireBatchUpdate{ActionType: action}
+	for _, e := range entities {
+		body.Entities = append(body.Entities, entityToWireMap(e, self.ngsiv1Compat))
+	}
+	return self.postNoContent(ctx, "batch update", "/v2/op/update", body, firstScope(scope))
+}
+
+// entityToWireMap renders e the way NGSIv2's op endpoints and
+// notifications expect an entity: "id" and "type" alongside one member
+// per attribute.
+func entityToWireMap(e Entity, v1Compat bool) map[string]interface{} {
+	wire := map[string]interface{}{
+		"id":   e.Id(),
+		"type": e.Type(),
+	}
+	for name, attr := range e.Attributes().values {
+		wire[name] = attr.toWireValue(v1Compat)
+	}
+	return wire
+}
+
+// BatchQueryEntity narrows a BatchQuery to entities matching an exact id
+// or an id pattern, optionally scoped to a type.
+type BatchQueryEntity struct {
+	ID        string
+	IDPattern string
+	Type      string
+}
+
+// BatchQuery describes a POST /v2/op/query request: which entities to
+// match, which attributes to project, and an optional NGSIv2 filter -
+// an attribute expression via Expression, a spatial filter via
+// GeoRelation/Geometry/Coordinates, or both.
+type BatchQuery struct {
+	Entities    []BatchQueryEntity
+	Attrs       []string
+	Expression  string
+	GeoRelation string
+	Geometry    string
+	Coordinates string
+}
+
+type wireBatchQueryEntity struct {
+	ID        string `json:"id,omitempty"`
+	IDPattern string `json:"idPattern,omitempty"`
+	Type      string `json:"type,omitempty"`
+}
+
+type wireBatchQueryExpression struct {
+	Q           string `json:"q,omitempty"`
+	GeoRel      string `json:"georel,omitempty"`
+	Geometry    string `json:"geometry,omitempty"`
+	Coordinates string `json:"coords,omitempty"`
+}
+
+type wireBatchQuery struct {
+	Entities   []wireBatchQueryEntity    `json:"entities,omitempty"`
+	Attrs      []string                  `json:"attrs,omitempty"`
+	Expression *wireBatchQueryExpression `json:"expression,omitempty"`
+}
+
+func (self BatchQuery) toWire() wireBatchQuery {
+	wire := wireBatchQuery{Attrs: self.Attrs}
+	for _, e := range self.Entities {
+		wire.Entities = append(wire.Entities, wireBatchQueryEntity{
+			ID:        e.ID,
+			IDPattern: e.IDPattern,
+			Type:      e.Type,
+		})
+	}
+
+	if self.Expression != "" || self.GeoRelation != "" || self.Geometry != "" || self.Coordinates != "" {
+		wire.Expression = &wireBatchQueryExpression{
+			Q:           self.Expression,
+			GeoRel:      self.GeoRelation,
+			Geometry:    self.Geometry,
+			Coordinates: self.Coordinates,
+		}
+	}
+	return wire
+}
+
+// BatchQuery runs a single query across many entities in one request,
+// wrapping POST /v2/op/query.
+func (self *Server) BatchQuery(ctx context.Context, query BatchQuery, f EntityFactory, scope ...Scope) ([]Entity, error) {
+	var data []map[string]json.RawMessage
+	if err := self.post(ctx, "batch query", "/v2/op/query", query.toWire(), &data, firstScope(scope)); err != nil {
+		return nil, err
+	}
+
+	entities := make([]Entity, 0, len(data))
+	for _, raw := range data {
+		entity, err := decodeNotifiedEntity(raw, f)
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, entity)
+	}
+	return entities, nil
+}
@@ -0,0 +1,82 @@
+package orion
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// Error reports a failure the broker itself reported, as opposed to a
+// transport-level failure (a network error, a non-JSON response, ...).
+// It carries the NGSI error payload Orion sends on non-2xx responses
+// ({"error": "...", "description": "..."}), or, for the NGSIv1 endpoints
+// that report failure inside a 200 response body, the equivalent fields
+// of the embedded statusCode.
+type Error struct {
+	// Code is the HTTP status Orion responded with, or the NGSIv1
+	// statusCode's numeric code for endpoints that embed it.
+	Code int
+	// ReasonPhrase is Orion's short error name, e.g. "NotFound" or
+	// "Unprocessable".
+	ReasonPhrase string
+	// Details is Orion's longer, human-readable description, if any.
+	Details string
+	// Operation names the Server method that issued the failing
+	// request, e.g. "create entity" or "list subscriptions".
+	Operation string
+}
+
+func (self *Error) Error() string {
+	if self.Details != "" {
+		return fmt.Sprintf("orion: %s: %s (%d %s)", self.Operation, self.Details, self.Code, self.ReasonPhrase)
+	}
+	return fmt.Sprintf("orion: %s: %d %s", self.Operation, self.Code, self.ReasonPhrase)
+}
+
+// Is reports whether target is an *Error with the same Code, so that
+// errors.Is(err, ErrNotFound) and friends work regardless of Details or
+// Operation.
+func (self *Error) Is(target error) bool {
+	other, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return self.Code == other.Code
+}
+
+// Sentinel errors for the NGSI status codes callers most often need to
+// branch on. Compare against them with errors.Is, not ==, since the
+// *Error returned by a Server carries a different Operation and Details.
+var (
+	ErrBadRequest    = &Error{Code: http.StatusBadRequest, ReasonPhrase: "BadRequest"}
+	ErrUnauthorized  = &Error{Code: http.StatusUnauthorized, ReasonPhrase: "Unauthorized"}
+	ErrNotFound      = &Error{Code: http.StatusNotFound, ReasonPhrase: "NotFound"}
+	ErrAlreadyExists = &Error{Code: http.StatusUnprocessableEntity, ReasonPhrase: "Unprocessable"}
+)
+
+type wireError struct {
+	Error       string `json:"error"`
+	Description string `json:"description"`
+}
+
+// decodeError builds an *Error for a non-2xx response, reading and
+// closing resp.Body. The NGSI error payload is decoded on a best-effort
+// basis: a response that isn't valid JSON still yields an *Error, just
+// without ReasonPhrase/Details filled in.
+func decodeError(resp *http.Response, operation string) error {
+	defer resp.Body.Close()
+
+	result := &Error{Code: resp.StatusCode, Operation: operation}
+	octets, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return result
+	}
+
+	var wire wireError
+	if err := json.Unmarshal(octets, &wire); err == nil {
+		result.ReasonPhrase = wire.Error
+		result.Details = wire.Description
+	}
+	return result
+}
@@ -0,0 +1,171 @@
+package orion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// NotificationHandler receives the entities carried by one notification,
+// decoded via the NotificationServer's EntityFactory.
+type NotificationHandler func(ctx context.Context, subscription SubscriptionID, entities []Entity)
+
+const defaultShutdownTimeout = 30 * time.Second
+
+// NotificationServer is an http.Handler that decodes the NGSIv2
+// notification payloads Orion posts to a subscription's notification URL
+// and dispatches the decoded entities to handlers registered by entity
+// type or by subscription id. Register it with an http.Server, or use
+// ListenAndServe for a self-contained one.
+type NotificationServer struct {
+	factory EntityFactory
+
+	mu              sync.RWMutex
+	byType          map[string][]NotificationHandler
+	bySubscription  map[SubscriptionID][]NotificationHandler
+	shutdownTimeout time.Duration
+}
+
+// NewNotificationServer creates a NotificationServer that builds entities
+// with f as notifications arrive.
+func NewNotificationServer(f EntityFactory) *NotificationServer {
+	return &NotificationServer{
+		factory:         f,
+		byType:          make(map[string][]NotificationHandler),
+		bySubscription:  make(map[SubscriptionID][]NotificationHandler),
+		shutdownTimeout: defaultShutdownTimeout,
+	}
+}
+
+// HandleType registers h to run for every notified entity of the given
+// type, regardless of which subscription triggered it.
+func (self *NotificationServer) HandleType(entityType string, h NotificationHandler) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.byType[entityType] = append(self.byType[entityType], h)
+}
+
+// HandleSubscription registers h to run for every notification from the
+// given subscription id, regardless of entity type.
+func (self *NotificationServer) HandleSubscription(id SubscriptionID, h NotificationHandler) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.bySubscription[id] = append(self.bySubscription[id], h)
+}
+
+// SetShutdownTimeout bounds how long ListenAndServe waits for in-flight
+// notifications to finish dispatching once its context is cancelled.
+func (self *NotificationServer) SetShutdownTimeout(d time.Duration) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.shutdownTimeout = d
+}
+
+type wireNotificationPayload struct {
+	SubscriptionID string                       `json:"subscriptionId"`
+	Data           []map[string]json.RawMessage `json:"data"`
+}
+
+func (self *NotificationServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	octets, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var payload wireNotificationPayload
+	if err := json.Unmarshal(octets, &payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entities := make([]Entity, 0, len(payload.Data))
+	for _, raw := range payload.Data {
+		entity, err := decodeNotifiedEntity(raw, self.factory)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		entities = append(entities, entity)
+	}
+
+	self.dispatch(r.Context(), SubscriptionID(payload.SubscriptionID), entities)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeNotifiedEntity turns one NGSIv2 flat entity object - an "id" and
+// "type" alongside one member per attribute - into an Entity built via f.
+func decodeNotifiedEntity(raw map[string]json.RawMessage, f EntityFactory) (Entity, error) {
+	var id, etype string
+	if err := json.Unmarshal(raw["id"], &id); err != nil {
+		return nil, fmt.Errorf("orion: decoding notified entity id: %w", err)
+	}
+	if err := json.Unmarshal(raw["type"], &etype); err != nil {
+		return nil, fmt.Errorf("orion: decoding notified entity type: %w", err)
+	}
+
+	attrs := NewAttributes()
+	for name, octets := range raw {
+		if name == "id" || name == "type" {
+			continue
+		}
+		var wire wireAttribute
+		if err := json.Unmarshal(octets, &wire); err != nil {
+			return nil, fmt.Errorf("orion: decoding notified attribute %q: %w", name, err)
+		}
+		attrs.Add(name, wire.toAttribute())
+	}
+
+	entity := f(etype, id)
+	entity.SetAttributes(attrs)
+	return entity, nil
+}
+
+func (self *NotificationServer) dispatch(ctx context.Context, id SubscriptionID, entities []Entity) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+
+	for _, h := range self.bySubscription[id] {
+		h(ctx, id, entities)
+	}
+
+	byType := make(map[string][]Entity, len(entities))
+	for _, e := range entities {
+		byType[e.Type()] = append(byType[e.Type()], e)
+	}
+	for etype, group := range byType {
+		for _, h := range self.byType[etype] {
+			h(ctx, id, group)
+		}
+	}
+}
+
+// ListenAndServe serves self on addr until ctx is cancelled, then shuts
+// the underlying http.Server down gracefully, giving in-flight
+// notification callbacks up to the configured shutdown timeout to finish.
+func (self *NotificationServer) ListenAndServe(ctx context.Context, addr string) error {
+	httpServer := &http.Server{Addr: addr, Handler: self}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		self.mu.RLock()
+		timeout := self.shutdownTimeout
+		self.mu.RUnlock()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return httpServer.Shutdown(shutdownCtx)
+	}
+}
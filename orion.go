@@ -2,6 +2,7 @@ package orion
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -10,11 +11,41 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// NGSIv2 attribute type names, as sent on the wire in the "type" field.
+const (
+	TypeText       = "Text"
+	TypeNumber     = "Number"
+	TypeBoolean    = "Boolean"
+	TypeDateTime   = "DateTime"
+	TypeGeoPoint   = "geo:point"
+	TypeStructured = "StructuredValue"
+)
+
+// GeoPoint is a WGS84 latitude/longitude pair, wired as NGSI's "geo:point"
+// type (a "lat, lon" string).
+type GeoPoint struct {
+	Lat float64
+	Lon float64
+}
+
+func (self GeoPoint) String() string {
+	return fmt.Sprintf("%g, %g", self.Lat, self.Lon)
+}
+
+// Attribute is a single NGSI attribute value together with its declared
+// type and, optionally, NGSI metadata (e.g. "timestamp", "unit").
+//
+// Value holds a Go-native representation of the declared Type: string for
+// TypeText, float64 for TypeNumber, bool for TypeBoolean, time.Time for
+// TypeDateTime, GeoPoint for TypeGeoPoint, and json.RawMessage for
+// TypeStructured.
 type Attribute struct {
-	Type  string
-	Value string
+	Type     string
+	Value    interface{}
+	Metadata map[string]Attribute
 }
 
 type Attributes struct {
@@ -27,24 +58,39 @@ func NewAttributes() Attributes {
 	}
 }
 
+// Add classifies value by its Go type and stores it under name. Passing an
+// Attribute directly stores it verbatim, which lets callers set Metadata or
+// pick a type Add can't infer (e.g. DateTime, geo:point, StructuredValue).
 func (self Attributes) Add(name string, value interface{}) error {
 	attr := Attribute{}
 	switch el := value.(type) {
 	case string:
-		attr.Type = "string"
+		attr.Type = TypeText
 		attr.Value = el
 	case int:
-		attr.Type = "int"
-		attr.Value = strconv.FormatInt(int64(el), 10)
+		attr.Type = TypeNumber
+		attr.Value = float64(el)
 	case int64:
-		attr.Type = "int"
-		attr.Value = strconv.FormatInt(el, 10)
+		attr.Type = TypeNumber
+		attr.Value = float64(el)
 	case float32:
-		attr.Type = "float"
-		attr.Value = strconv.FormatFloat(float64(el), 'f', -1, 32)
+		attr.Type = TypeNumber
+		attr.Value = float64(el)
 	case float64:
-		attr.Type = "int"
-		attr.Value = strconv.FormatFloat(float64(el), 'f', -1, 64)
+		attr.Type = TypeNumber
+		attr.Value = el
+	case bool:
+		attr.Type = TypeBoolean
+		attr.Value = el
+	case time.Time:
+		attr.Type = TypeDateTime
+		attr.Value = el
+	case GeoPoint:
+		attr.Type = TypeGeoPoint
+		attr.Value = el
+	case json.RawMessage:
+		attr.Type = TypeStructured
+		attr.Value = el
 	case Attribute:
 		attr = el
 	default:
@@ -62,49 +108,139 @@ func (self Attributes) Get(name string) (Attribute, bool) {
 
 func (self Attributes) GetString(name string) (string, bool) {
 	entry, ok := self.values[name]
-	if ok {
-		return entry.Value, true
+	if !ok || entry.Type != TypeText {
+		return "", false
 	}
-	return "", false
+	value, ok := entry.Value.(string)
+	return value, ok
 }
 
+// GetInt returns the attribute's Number value truncated to an int64. Like
+// GetFloat, it only honors attributes declared as TypeNumber.
 func (self Attributes) GetInt(name string) (int64, bool) {
-	entry, ok := self.values[name]
-	if ok {
-		value, err := strconv.ParseInt(entry.Value, 10, 64)
-		if err != nil {
-			return 0, true
-		}
-		return value, true
+	value, ok := self.GetFloat(name)
+	if !ok {
+		return 0, false
 	}
-	return 0, false
+	return int64(value), true
 }
 
 func (self Attributes) GetFloat(name string) (float64, bool) {
 	entry, ok := self.values[name]
-	if ok {
-		value, err := strconv.ParseFloat(entry.Value, 64)
-		if err != nil {
-			return 0, true
-		}
-		return value, true
+	if !ok || entry.Type != TypeNumber {
+		return 0, false
 	}
-	return 0, false
+	value, ok := entry.Value.(float64)
+	return value, ok
 }
 
-func (self Attributes) toWire() wireAttributes {
+func (self Attributes) GetBool(name string) (bool, bool) {
+	entry, ok := self.values[name]
+	if !ok || entry.Type != TypeBoolean {
+		return false, false
+	}
+	value, ok := entry.Value.(bool)
+	return value, ok
+}
+
+func (self Attributes) GetDateTime(name string) (time.Time, bool) {
+	entry, ok := self.values[name]
+	if !ok || entry.Type != TypeDateTime {
+		return time.Time{}, false
+	}
+	value, ok := entry.Value.(time.Time)
+	return value, ok
+}
+
+func (self Attributes) GetGeoPoint(name string) (GeoPoint, bool) {
+	entry, ok := self.values[name]
+	if !ok || entry.Type != TypeGeoPoint {
+		return GeoPoint{}, false
+	}
+	value, ok := entry.Value.(GeoPoint)
+	return value, ok
+}
+
+// GetStructured returns the raw JSON of a TypeStructured attribute, for
+// callers that want to unmarshal it into their own type.
+func (self Attributes) GetStructured(name string) (json.RawMessage, bool) {
+	entry, ok := self.values[name]
+	if !ok || entry.Type != TypeStructured {
+		return nil, false
+	}
+	value, ok := entry.Value.(json.RawMessage)
+	return value, ok
+}
+
+// toWire renders the attributes for the wire. When v1Compat is true every
+// value is rendered as a plain string, matching the deprecated NGSIv1
+// wire format; otherwise values are rendered using their native NGSIv2
+// JSON representation (numbers, booleans, ISO-8601 strings, objects...).
+func (self Attributes) toWire(v1Compat bool) wireAttributes {
 	var attrs []wireAttribute
 	for key, value := range self.values {
-		attr := wireAttribute{
-			Name:  key,
-			Type:  value.Type,
-			Value: value.Value,
-		}
-		attrs = append(attrs, attr)
+		attrs = append(attrs, value.toWire(key, v1Compat))
 	}
 	return wireAttributes{attrs}
 }
 
+func (self Attribute) toWire(name string, v1Compat bool) wireAttribute {
+	wire := wireAttribute{Name: name, Type: self.Type}
+	if v1Compat {
+		wire.Value = self.stringValue()
+	} else {
+		wire.Value = self.wireValue()
+	}
+
+	if len(self.Metadata) > 0 {
+		wire.Metadata = make(map[string]wireAttribute, len(self.Metadata))
+		for key, value := range self.Metadata {
+			wire.Metadata[key] = value.toWire(key, v1Compat)
+		}
+	}
+	return wire
+}
+
+// toWireValue renders self the way NGSIv2's flat entity representation
+// expects an attribute: an unnamed {type, value, metadata} object, meant
+// to be assigned under the attribute's name in the enclosing entity map.
+func (self Attribute) toWireValue(v1Compat bool) wireAttribute {
+	return self.toWire("", v1Compat)
+}
+
+// wireValue renders Value using its native NGSIv2 JSON representation.
+func (self Attribute) wireValue() interface{} {
+	switch value := self.Value.(type) {
+	case time.Time:
+		return value.Format(time.RFC3339)
+	case GeoPoint:
+		return value.String()
+	default:
+		return value
+	}
+}
+
+// stringValue renders Value as a string, for the deprecated NGSIv1
+// wire format where every attribute value is sent as a string.
+func (self Attribute) stringValue() string {
+	switch value := self.Value.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	case time.Time:
+		return value.Format(time.RFC3339)
+	case GeoPoint:
+		return value.String()
+	case json.RawMessage:
+		return string(value)
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
 type Entity interface {
 	Id() string
 	Type() string
@@ -120,20 +256,88 @@ func (self Page) Next() Page {
 	return Page(uint(self) + 1)
 }
 
+const (
+	defaultTimeout    = 30 * time.Second
+	defaultMaxRetries = 3
+	defaultRetryWait  = 200 * time.Millisecond
+)
+
 type Server struct {
-	server_url string
+	server_url   string
+	client       *http.Client
+	timeout      time.Duration
+	maxRetries   int
+	retryWait    time.Duration
+	ngsiv1Compat bool
+	service      string
+	servicePath  string
+}
+
+// Option configures a Server at construction time.
+type Option func(*Server)
+
+// WithHTTPClient overrides the http.Client used for every request, letting
+// callers configure TLS, proxies, or connection pooling.
+func WithHTTPClient(c *http.Client) Option {
+	return func(self *Server) {
+		self.client = c
+	}
 }
 
-func NewServer(u string) *Server {
-	return &Server{
-		strings.TrimSuffix(u, "/"),
+// WithClient is an alias for WithHTTPClient.
+func WithClient(c *http.Client) Option {
+	return WithHTTPClient(c)
+}
+
+// WithTimeout bounds the duration of a single request, including retries.
+// A zero duration disables the timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(self *Server) {
+		self.timeout = d
 	}
 }
 
+// WithRetries sets how many additional attempts are made after a transient
+// network error or a 5xx response, and the base delay between attempts.
+// The delay doubles after each retry.
+func WithRetries(max int, wait time.Duration) Option {
+	return func(self *Server) {
+		self.maxRetries = max
+		self.retryWait = wait
+	}
+}
+
+// WithNGSIv1Compat makes Attribute values render on the wire as plain
+// strings, matching the deprecated NGSIv1 wire format, instead of their
+// native NGSIv2 JSON representation. Off by default.
+func WithNGSIv1Compat(enabled bool) Option {
+	return func(self *Server) {
+		self.ngsiv1Compat = enabled
+	}
+}
+
+func NewServer(u string, opts ...Option) *Server {
+	self := &Server{
+		server_url: strings.TrimSuffix(u, "/"),
+		client:     http.DefaultClient,
+		timeout:    defaultTimeout,
+		maxRetries: defaultMaxRetries,
+		retryWait:  defaultRetryWait,
+	}
+	for _, opt := range opts {
+		opt(self)
+	}
+	return self
+}
+
 type wireAttribute struct {
-	Name  string `json:"name"`
-	Type  string `json:"type"`
-	Value string `json:"value"`
+	// Name is only meaningful in wireAttributes' array form; it is
+	// omitted so an Attribute can also be marshaled bare as one member
+	// of a flat NGSIv2 entity object (see Attribute.toWireValue).
+	Name     string                   `json:"name,omitempty"`
+	Type     string                   `json:"type"`
+	Value    interface{}              `json:"value"`
+	Metadata map[string]wireAttribute `json:"metadata,omitempty"`
 }
 
 type wireAttributes struct {
@@ -143,14 +347,100 @@ type wireAttributes struct {
 func (self wireAttributes) toAttributes() Attributes {
 	attrs := NewAttributes()
 	for _, el := range self.Attributes {
-		attrs.Add(el.Name, Attribute{
-			Type:  el.Type,
-			Value: el.Value,
-		})
+		attrs.Add(el.Name, el.toAttribute())
 	}
 	return attrs
 }
 
+// toAttribute decodes a wire value into its Go-native representation,
+// accepting both a native NGSIv2 JSON value and the legacy NGSIv1
+// string-typed value for the same declared Type.
+func (self wireAttribute) toAttribute() Attribute {
+	attr := Attribute{Type: self.Type}
+	switch self.Type {
+	case TypeNumber:
+		attr.Value = wireToFloat(self.Value)
+	case TypeBoolean:
+		attr.Value = wireToBool(self.Value)
+	case TypeDateTime:
+		attr.Value = wireToDateTime(self.Value)
+	case TypeGeoPoint:
+		attr.Value = wireToGeoPoint(self.Value)
+	case TypeStructured:
+		attr.Value = wireToRawMessage(self.Value)
+	default:
+		attr.Value = wireToString(self.Value)
+	}
+
+	if len(self.Metadata) > 0 {
+		attr.Metadata = make(map[string]Attribute, len(self.Metadata))
+		for key, value := range self.Metadata {
+			attr.Metadata[key] = value.toAttribute()
+		}
+	}
+	return attr
+}
+
+func wireToFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	return 0
+}
+
+func wireToBool(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	}
+	return false
+}
+
+func wireToDateTime(value interface{}) time.Time {
+	s, _ := value.(string)
+	t, _ := time.Parse(time.RFC3339, s)
+	return t
+}
+
+func wireToGeoPoint(value interface{}) GeoPoint {
+	s, _ := value.(string)
+	lat, lon := s, ""
+	if idx := strings.Index(s, ","); idx >= 0 {
+		lat, lon = s[:idx], s[idx+1:]
+	}
+	point := GeoPoint{}
+	point.Lat, _ = strconv.ParseFloat(strings.TrimSpace(lat), 64)
+	point.Lon, _ = strconv.ParseFloat(strings.TrimSpace(lon), 64)
+	return point
+}
+
+func wireToRawMessage(value interface{}) json.RawMessage {
+	// NGSIv1 sends a structured value as a string holding its JSON.
+	if s, ok := value.(string); ok && json.Valid([]byte(s)) {
+		return json.RawMessage(s)
+	}
+	octets, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	return octets
+}
+
+func wireToString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	octets, _ := json.Marshal(value)
+	return string(octets)
+}
+
 type wireId struct {
 	Id        string `json:"id"`
 	IsPattern bool   `json:"isPattern,string"`
@@ -184,44 +474,44 @@ type wireQueryContextResponse struct {
 	Elements []wireQueryContextElement `json:"contextResponses"`
 }
 
-func (self *Server) NewEntity(e Entity) error {
+func (self *Server) NewEntity(ctx context.Context, e Entity, scope ...Scope) error {
 	var result wireAlteredContextResponse
 
 	u := fmt.Sprintf("/v1/contextEntities/type/%s/id/%s", e.Type(), e.Id())
-	err := self.post(u, e.Attributes().toWire(), &result)
+	err := self.post(ctx, "create entity", u, e.Attributes().toWire(self.ngsiv1Compat), &result, firstScope(scope))
 	if err != nil {
 		return err
 	}
 
 	status := result.Elements[0]
 	if status.Code != 200 {
-		return fmt.Errorf("entity creation failed. code=%d message=%s", status.Code, status.Message)
+		return &Error{Code: int(status.Code), ReasonPhrase: status.Message, Operation: "create entity"}
 	}
 
 	return nil
 }
 
-func (self *Server) DeleteEntity(e Entity) error {
+func (self *Server) DeleteEntity(ctx context.Context, e Entity, scope ...Scope) error {
 	u := fmt.Sprintf("/v1/contextEntities/type/%s/id/%s", e.Type(), e.Id())
 	response := wireStatus{}
-	err := self.delete(u, &response)
+	err := self.delete(ctx, "delete entity", u, &response, firstScope(scope))
 
 	if err != nil {
 		return err
 	}
 
 	if response.Code != 200 {
-		return fmt.Errorf("entity deletion failed. code=%d message=%s", response.Code, response.Message)
+		return &Error{Code: int(response.Code), ReasonPhrase: response.Message, Operation: "delete entity"}
 	}
 
 	return nil
 }
 
-func (self *Server) UpdateEntity(e Entity) error {
+func (self *Server) UpdateEntity(ctx context.Context, e Entity, scope ...Scope) error {
 	var result wireAlteredContextResponse
 
 	u := fmt.Sprintf("/v1/contextEntities/type/%s/id/%s", e.Type(), e.Id())
-	err := self.put(u, e.Attributes().toWire(), &result)
+	err := self.put(ctx, "update entity", u, e.Attributes().toWire(self.ngsiv1Compat), &result, firstScope(scope))
 	if err != nil {
 		return err
 	}
@@ -232,13 +522,13 @@ func (self *Server) UpdateEntity(e Entity) error {
 	}
 	status := result.Elements[0]
 	if status.Code != 200 {
-		return fmt.Errorf("entity creation failed. code=%d message=%s", status.Code, status.Message)
+		return &Error{Code: int(status.Code), ReasonPhrase: status.Message, Operation: "update entity"}
 	}
 
 	return nil
 }
 
-func (self *Server) EntitiesByType(entity_type string, page Page, f EntityFactory) ([]Entity, error) {
+func (self *Server) EntitiesByType(ctx context.Context, entity_type string, page Page, f EntityFactory, scope ...Scope) ([]Entity, error) {
 	limit := int64(100)
 	offset := int64(page) * limit
 	u := fmt.Sprintf("/v1/contextEntityTypes/%s?limit=%s&offset=%s",
@@ -247,51 +537,76 @@ func (self *Server) EntitiesByType(entity_type string, page Page, f EntityFactor
 		url.QueryEscape(strconv.FormatInt(offset, 10)))
 
 	result := wireQueryContextResponse{}
-	err := self.get(u, &result)
+	err := self.get(ctx, "list entities", u, &result, firstScope(scope))
 	if err != nil {
 		return nil, err
 	}
 
 	var output []Entity
 	for _, el := range result.Elements {
-		ctx := &el.ContextElement
-		entity := f(ctx.Type, ctx.Id)
-		entity.SetAttributes(ctx.wireAttributes.toAttributes())
+		ce := &el.ContextElement
+		entity := f(ce.Type, ce.Id)
+		entity.SetAttributes(ce.wireAttributes.toAttributes())
 		output = append(output, entity)
 	}
 	return output, nil
 }
 
-func (self *Server) AllEntitiesByType(entity_type string, f EntityFactory) ([]Entity, error) {
+func (self *Server) AllEntitiesByType(ctx context.Context, entity_type string, f EntityFactory, scope ...Scope) ([]Entity, error) {
+	out := make(chan Entity)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- self.EntitiesByTypeStream(ctx, entity_type, f, out, scope...)
+	}()
+
 	var output []Entity
+	for el := range out {
+		output = append(output, el)
+	}
+	return output, <-errCh
+}
+
+// EntitiesByTypeStream paginates through every entity of entity_type,
+// sending each one to out as its page arrives, so callers pulling large
+// tenant datasets don't have to hold them all in memory at once. It
+// closes out before returning, whether it returns nil or an error.
+func (self *Server) EntitiesByTypeStream(ctx context.Context, entity_type string, f EntityFactory, out chan<- Entity, scope ...Scope) error {
+	defer close(out)
+
 	page := Page(0)
 	for {
-		chunk, err := self.EntitiesByType(entity_type, page, f)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		chunk, err := self.EntitiesByType(ctx, entity_type, page, f, scope...)
 		if err != nil {
-			return output, err
+			return err
 		}
 		if len(chunk) == 0 {
-			break
+			return nil
 		}
 		for _, el := range chunk {
-			output = append(output, el)
+			select {
+			case out <- el:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 		}
 		page = page.Next()
 	}
-	return output, nil
 }
 
-func (self *Server) EntityById(e Entity) error {
+func (self *Server) EntityById(ctx context.Context, e Entity, scope ...Scope) error {
 	u := fmt.Sprintf("/v1/contextEntities/type/%s/id/%s", e.Type(), e.Id())
 
 	result := wireQueryContextElement{}
-	err := self.get(u, &result)
+	err := self.get(ctx, "get entity", u, &result, firstScope(scope))
 	if err != nil {
 		return err
 	}
 
 	if result.Code != 200 {
-		return fmt.Errorf("entity lookup failed. code=%d message=%s", result.Code, result.Message)
+		return &Error{Code: int(result.Code), ReasonPhrase: result.Message, Operation: "get entity"}
 	}
 
 	e.SetAttributes(result.ContextElement.wireAttributes.toAttributes())
@@ -299,26 +614,26 @@ func (self *Server) EntityById(e Entity) error {
 	return nil
 }
 
-func (self *Server) CheckEntity(eType string, eID string) (r bool) {
+func (self *Server) CheckEntity(ctx context.Context, eType string, eID string, scope ...Scope) (r bool) {
 	u := fmt.Sprintf("/v1/contextEntities/type/%s/id/%s", eType, eID)
 
 	result := wireQueryContextElement{}
-	err := self.get(u, &result)
+	err := self.get(ctx, "check entity", u, &result, firstScope(scope))
 	r = err == nil && result.Code == 200
 	return
 }
 
-func (self *Server) get(path string, response interface{}) error {
+func (self *Server) get(ctx context.Context, operation string, path string, response interface{}, scope *Scope) error {
 	u := self.server_url + path
 	req, err := http.NewRequest("GET", u, nil)
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Accept", "application/json")
-	return self.do(req, response)
+	return self.do(ctx, operation, req, response, scope)
 }
 
-func (self *Server) post(path string, body interface{}, response interface{}) error {
+func (self *Server) post(ctx context.Context, operation string, path string, body interface{}, response interface{}, scope *Scope) error {
 	octets, err := json.Marshal(body)
 	if err != nil {
 		return err
@@ -331,10 +646,10 @@ func (self *Server) post(path string, body interface{}, response interface{}) er
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
-	return self.do(req, response)
+	return self.do(ctx, operation, req, response, scope)
 }
 
-func (self *Server) put(path string, body interface{}, response interface{}) error {
+func (self *Server) put(ctx context.Context, operation string, path string, body interface{}, response interface{}, scope *Scope) error {
 	octets, err := json.Marshal(body)
 	if err != nil {
 		return err
@@ -347,21 +662,90 @@ func (self *Server) put(path string, body interface{}, response interface{}) err
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
-	return self.do(req, response)
+	return self.do(ctx, operation, req, response, scope)
 }
 
-func (self *Server) delete(path string, response interface{}) error {
+func (self *Server) delete(ctx context.Context, operation string, path string, response interface{}, scope *Scope) error {
 	u := self.server_url + path
 	req, err := http.NewRequest("DELETE", u, nil)
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Accept", "application/json")
-	return self.do(req, response)
+	return self.do(ctx, operation, req, response, scope)
 }
 
-func (self *Server) do(req *http.Request, response interface{}) error {
-	resp, err := http.DefaultClient.Do(req)
+// postRaw behaves like post, but returns the raw response instead of
+// decoding its body, for callers that need response headers (e.g. a
+// Location header) rather than a JSON payload.
+func (self *Server) postRaw(ctx context.Context, operation string, path string, body interface{}, scope *Scope) (*http.Response, error) {
+	octets, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	u := self.server_url + path
+	req, err := http.NewRequest("POST", u, bytes.NewReader(octets))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return self.doRaw(ctx, operation, req, scope)
+}
+
+// postNoContent behaves like post, but for endpoints that respond with
+// an empty body (e.g. NGSIv2's POST /v2/op/update).
+func (self *Server) postNoContent(ctx context.Context, operation string, path string, body interface{}, scope *Scope) error {
+	resp, err := self.postRaw(ctx, operation, path, body, scope)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// patchNoContent behaves like put, but for endpoints that respond with an
+// empty body (e.g. NGSIv2's PATCH /v2/subscriptions/{id}).
+func (self *Server) patchNoContent(ctx context.Context, operation string, path string, body interface{}, scope *Scope) error {
+	octets, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	u := self.server_url + path
+	req, err := http.NewRequest("PATCH", u, bytes.NewReader(octets))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := self.doRaw(ctx, operation, req, scope)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// deleteNoContent behaves like delete, but for endpoints that respond
+// with an empty body (e.g. NGSIv2's DELETE /v2/subscriptions/{id}).
+func (self *Server) deleteNoContent(ctx context.Context, operation string, path string, scope *Scope) error {
+	u := self.server_url + path
+	req, err := http.NewRequest("DELETE", u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := self.doRaw(ctx, operation, req, scope)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (self *Server) do(ctx context.Context, operation string, req *http.Request, response interface{}, scope *Scope) error {
+	resp, err := self.doRaw(ctx, operation, req, scope)
 	if err != nil {
 		return err
 	}
@@ -378,3 +762,98 @@ func (self *Server) do(req *http.Request, response interface{}) error {
 	}
 	return nil
 }
+
+// doRaw issues req with the configured timeout and retry policy, and
+// returns the raw response for callers that need to inspect headers (e.g.
+// a Location header) or that expect no body. scope, if non-nil, overrides
+// the Server's own Fiware-Service / Fiware-ServicePath tenant for this
+// request only. A non-2xx response is turned into an *Error carrying
+// operation rather than returned as-is.
+func (self *Server) doRaw(ctx context.Context, operation string, req *http.Request, scope *Scope) (*http.Response, error) {
+	service, servicePath := self.service, self.servicePath
+	if scope != nil {
+		service, servicePath = scope.Service, scope.ServicePath
+	}
+	if err := validateScope(service, servicePath); err != nil {
+		return nil, err
+	}
+	if service != "" {
+		req.Header.Set("Fiware-Service", service)
+	}
+	if servicePath != "" {
+		req.Header.Set("Fiware-ServicePath", servicePath)
+	}
+
+	if self.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, self.timeout)
+		defer cancel()
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := self.doWithRetry(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, decodeError(resp, operation)
+	}
+
+	// Read the body while ctx is still live: the deferred cancel above
+	// would otherwise abort a body the caller reads after doRaw returns.
+	octets, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(octets))
+	return resp, nil
+}
+
+// doWithRetry issues req, retrying on transient network errors and 5xx
+// responses with exponential backoff. The wait between attempts, and the
+// request itself, are both cancelled as soon as req's context is done.
+func (self *Server) doWithRetry(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	var lastErr error
+
+	for attempt := 0; attempt <= self.maxRetries; attempt++ {
+		if attempt > 0 {
+			wait := self.retryWait * time.Duration(uint(1)<<uint(attempt-1))
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			}
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req.Body = body
+			}
+		}
+
+		resp, err := self.client.Do(req)
+		if err != nil {
+			lastErr = err
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+
+		if resp.StatusCode >= 500 && attempt < self.maxRetries {
+			resp.Body.Close()
+			lastErr = fmt.Errorf("orion: server error, status=%d", resp.StatusCode)
+			continue
+		}
+
+		return resp, nil
+	}
+
+	return nil, lastErr
+}
@@ -0,0 +1,197 @@
+package orion
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SubscriptionID identifies an NGSIv2 subscription, as returned by
+// Server.Subscribe.
+type SubscriptionID string
+
+// SubscriptionEntity selects the entities a Subscription watches. Set ID
+// to match a single entity, or IDPattern to match a regular expression
+// over entity ids; Type is optional in either case.
+type SubscriptionEntity struct {
+	ID        string
+	IDPattern string
+	Type      string
+}
+
+// Subscription describes an NGSIv2 subscription: which entities and
+// attribute changes to watch, where to deliver notifications, and how
+// often.
+type Subscription struct {
+	Description string
+	Entities    []SubscriptionEntity
+
+	// Attrs restricts the condition to changes of these attributes.
+	// Empty means any attribute change triggers the subscription.
+	Attrs []string
+	// Expression is an NGSIv2 filter expression (the subscription's
+	// "q"), evaluated in addition to Attrs.
+	Expression string
+
+	NotifyURL string
+	// NotifyAttrs restricts the attributes included in notifications.
+	// Empty means all attributes are included.
+	NotifyAttrs []string
+	Throttling  time.Duration
+
+	// Expires is the absolute time after which the subscription is no
+	// longer active. The zero value means it never expires.
+	Expires time.Time
+}
+
+type wireSubscriptionExpression struct {
+	Q string `json:"q,omitempty"`
+}
+
+type wireSubscriptionCondition struct {
+	Attrs      []string                    `json:"attrs,omitempty"`
+	Expression *wireSubscriptionExpression `json:"expression,omitempty"`
+}
+
+type wireSubscriptionEntity struct {
+	ID        string `json:"id,omitempty"`
+	IDPattern string `json:"idPattern,omitempty"`
+	Type      string `json:"type,omitempty"`
+}
+
+type wireSubscriptionSubject struct {
+	Entities  []wireSubscriptionEntity  `json:"entities"`
+	Condition wireSubscriptionCondition `json:"condition,omitempty"`
+}
+
+type wireSubscriptionHTTP struct {
+	URL string `json:"url"`
+}
+
+type wireSubscriptionNotification struct {
+	HTTP  wireSubscriptionHTTP `json:"http"`
+	Attrs []string             `json:"attrs,omitempty"`
+}
+
+type wireSubscription struct {
+	ID           string                       `json:"id,omitempty"`
+	Description  string                       `json:"description,omitempty"`
+	Subject      wireSubscriptionSubject      `json:"subject"`
+	Notification wireSubscriptionNotification `json:"notification"`
+	Expires      *time.Time                   `json:"expires,omitempty"`
+	Throttling   int64                        `json:"throttling,omitempty"`
+}
+
+func (self Subscription) toWire() wireSubscription {
+	wire := wireSubscription{
+		Description: self.Description,
+		Subject: wireSubscriptionSubject{
+			Condition: wireSubscriptionCondition{Attrs: self.Attrs},
+		},
+		Notification: wireSubscriptionNotification{
+			HTTP:  wireSubscriptionHTTP{URL: self.NotifyURL},
+			Attrs: self.NotifyAttrs,
+		},
+	}
+
+	for _, e := range self.Entities {
+		wire.Subject.Entities = append(wire.Subject.Entities, wireSubscriptionEntity{
+			ID:        e.ID,
+			IDPattern: e.IDPattern,
+			Type:      e.Type,
+		})
+	}
+
+	if self.Expression != "" {
+		wire.Subject.Condition.Expression = &wireSubscriptionExpression{Q: self.Expression}
+	}
+	if !self.Expires.IsZero() {
+		expires := self.Expires
+		wire.Expires = &expires
+	}
+	if self.Throttling > 0 {
+		wire.Throttling = int64(self.Throttling / time.Second)
+	}
+
+	return wire
+}
+
+func (self wireSubscription) toSubscription() Subscription {
+	sub := Subscription{
+		Description: self.Description,
+		Attrs:       self.Subject.Condition.Attrs,
+		NotifyURL:   self.Notification.HTTP.URL,
+		NotifyAttrs: self.Notification.Attrs,
+		Throttling:  time.Duration(self.Throttling) * time.Second,
+	}
+
+	if self.Subject.Condition.Expression != nil {
+		sub.Expression = self.Subject.Condition.Expression.Q
+	}
+	if self.Expires != nil {
+		sub.Expires = *self.Expires
+	}
+	for _, e := range self.Subject.Entities {
+		sub.Entities = append(sub.Entities, SubscriptionEntity{
+			ID:        e.ID,
+			IDPattern: e.IDPattern,
+			Type:      e.Type,
+		})
+	}
+
+	return sub
+}
+
+// Subscribe registers sub with the broker and returns the id it was
+// assigned, wrapping POST /v2/subscriptions.
+func (self *Server) Subscribe(ctx context.Context, sub Subscription, scope ...Scope) (SubscriptionID, error) {
+	resp, err := self.postRaw(ctx, "subscribe", "/v2/subscriptions", sub.toWire(), firstScope(scope))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	location := resp.Header.Get("Location")
+	id := location[strings.LastIndex(location, "/")+1:]
+	if id == "" {
+		return "", fmt.Errorf("orion: subscribe response carried no subscription id")
+	}
+	return SubscriptionID(id), nil
+}
+
+// UpdateSubscription replaces the fields of an existing subscription,
+// wrapping PATCH /v2/subscriptions/{id}.
+func (self *Server) UpdateSubscription(ctx context.Context, id SubscriptionID, sub Subscription, scope ...Scope) error {
+	u := fmt.Sprintf("/v2/subscriptions/%s", url.PathEscape(string(id)))
+	return self.patchNoContent(ctx, "update subscription", u, sub.toWire(), firstScope(scope))
+}
+
+// UnsubscribeContext cancels a subscription, wrapping
+// DELETE /v2/subscriptions/{id}.
+func (self *Server) UnsubscribeContext(ctx context.Context, id SubscriptionID, scope ...Scope) error {
+	u := fmt.Sprintf("/v2/subscriptions/%s", url.PathEscape(string(id)))
+	return self.deleteNoContent(ctx, "unsubscribe", u, firstScope(scope))
+}
+
+// ListSubscriptions returns every subscription registered with the
+// broker, wrapping GET /v2/subscriptions.
+func (self *Server) ListSubscriptions(ctx context.Context, scope ...Scope) ([]Subscription, error) {
+	const limit = 100
+	subs := []Subscription{}
+	for offset := 0; ; offset += limit {
+		var wire []wireSubscription
+		u := fmt.Sprintf("/v2/subscriptions?limit=%d&offset=%d", limit, offset)
+		if err := self.get(ctx, "list subscriptions", u, &wire, firstScope(scope)); err != nil {
+			return nil, err
+		}
+
+		for _, w := range wire {
+			subs = append(subs, w.toSubscription())
+		}
+		if len(wire) < limit {
+			return subs, nil
+		}
+	}
+}
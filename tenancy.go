@@ -0,0 +1,65 @@
+package orion
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidScope is returned when a Scope's Service or ServicePath fails
+// validation, before any HTTP request is issued.
+var ErrInvalidScope = errors.New("orion: invalid tenant scope")
+
+var serviceNamePattern = regexp.MustCompile(`^[a-z0-9]{1,50}$`)
+
+const maxServicePathSegments = 10
+
+// Scope carries the Fiware-Service / Fiware-ServicePath headers Orion
+// uses for multi-tenancy. Omitting a Scope means "use the Server's own
+// tenant, if any"; a Scope that is passed replaces that tenant entirely,
+// so a zero Scope sends no tenant headers at all.
+type Scope struct {
+	Service     string
+	ServicePath string
+}
+
+// WithTenant returns a shallow copy of self scoped to service and
+// servicePath: every request the copy issues carries them as the
+// Fiware-Service / Fiware-ServicePath headers, unless overridden by a
+// per-call Scope.
+func (self *Server) WithTenant(service, servicePath string) *Server {
+	scoped := *self
+	scoped.service = service
+	scoped.servicePath = servicePath
+	return &scoped
+}
+
+// firstScope returns the first scope in an optional trailing ...Scope
+// argument, or nil if none was given, letting exported methods accept an
+// optional per-call Scope while internal helpers deal in *Scope.
+func firstScope(scope []Scope) *Scope {
+	if len(scope) == 0 {
+		return nil
+	}
+	return &scope[0]
+}
+
+// validateScope checks that service and servicePath are well-formed
+// before they're sent as headers. An empty string is always valid for
+// either field, since it means "no tenant header for this one".
+func validateScope(service, servicePath string) error {
+	if service != "" && !serviceNamePattern.MatchString(service) {
+		return ErrInvalidScope
+	}
+
+	if servicePath != "" {
+		if !strings.HasPrefix(servicePath, "/") {
+			return ErrInvalidScope
+		}
+		if strings.Count(servicePath, "/") > maxServicePathSegments {
+			return ErrInvalidScope
+		}
+	}
+
+	return nil
+}